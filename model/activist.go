@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,7 +20,12 @@ SELECT
   chapter,
   phone,
   location,
-  facebook
+  facebook,
+  row_status,
+  deleted_at,
+  self_delete,
+  delete_reason,
+  role
 FROM activists
 `
 
@@ -32,6 +38,11 @@ SELECT
   phone,
   location,
   facebook,
+  row_status,
+  deleted_at,
+  self_delete,
+  delete_reason,
+  role,
   activist_level,
   exclude_from_leaderboard,
   core_staff,
@@ -44,13 +55,21 @@ FROM activists a
 
 LEFT JOIN event_attendance ea
   ON ea.activist_id = a.id
- 
+
 LEFT JOIN events e
   ON ea.event_id = e.id
 `
 
-const descOrder int = 2
-const ascOrder int = 1
+// RowStatus is the lifecycle state of an activist row. Archiving or hiding
+// an activist preserves their attendance history while excluding them from
+// the default result set.
+type RowStatus string
+
+const (
+	RowStatusActive   RowStatus = "ACTIVE"
+	RowStatusArchived RowStatus = "ARCHIVED"
+	RowStatusHidden   RowStatus = "HIDDEN"
+)
 
 /** Type Definitions */
 
@@ -63,6 +82,11 @@ type User struct {
 	Location         sql.NullString `db:"location"`
 	Facebook         string         `db:"facebook"`
 	LiberationPledge int            `db:"liberation_pledge"`
+	RowStatus        RowStatus      `db:"row_status"`
+	DeletedAt        *time.Time     `db:"deleted_at"`
+	SelfDelete       int            `db:"self_delete"`
+	DeleteReason     sql.NullString `db:"delete_reason"`
+	Role             Role           `db:"role"`
 }
 
 type UserEventData struct {
@@ -102,12 +126,40 @@ type UserJSON struct {
 	LiberationPledge       int    `json:"liberation_pledge"`
 	GlobalTeamMember       int    `json:"global_team_member"`
 	ActivistLevel          string `json:"activist_level"`
+	// Settings is only populated by GetUserJSON, not GetUsersJSON, so
+	// listing activists doesn't pay for a settings lookup per row.
+	Settings *UserSettings `json:"settings,omitempty"`
+}
+
+// FindUsersOptions controls the row-status filtering shared by GetUsers,
+// GetUsersExtra, and getUserRange. A nil RowStatus defaults to active
+// activists only; a pointer to the empty string opts into every status.
+type FindUsersOptions struct {
+	RowStatus *string `json:"row_status"`
 }
 
 type UserOptionsJSON struct {
 	Name  string `json:"name"`
 	Limit int    `json:"limit"`
-	Order int    `json:"order"`
+	Order Order  `json:"order"`
+	// Cursor is a base64-encoded, HMAC-signed opaque token produced by a
+	// previous GetUserRangeJSON call. When set, it takes precedence over
+	// Name/Order/Limit for determining where the page starts.
+	Cursor string `json:"cursor"`
+	FindUsersOptions
+}
+
+// buildRowStatusFilter returns the SQL predicate and bind argument needed to
+// apply a FindUsersOptions.RowStatus filter, and whether one applies at all.
+func buildRowStatusFilter(rowStatus *string) (clause string, arg interface{}, ok bool) {
+	status := string(RowStatusActive)
+	if rowStatus != nil {
+		if *rowStatus == "" {
+			return "", nil, false
+		}
+		status = *rowStatus
+	}
+	return "row_status = ?", status, true
 }
 
 /** Functions and Methods */
@@ -121,23 +173,58 @@ func GetUserJSON(db *sqlx.DB, userID int) (UserJSON, error) {
 	if err != nil {
 		return UserJSON{}, err
 	}
-	return users[0], nil
+	user := users[0]
+
+	settings, err := GetUserSettings(db, userID)
+	if err != nil {
+		return UserJSON{}, err
+	}
+	user.Settings = &settings
+
+	return user, nil
 }
 
-func GetUserRangeJSON(db *sqlx.DB, userOptions UserOptionsJSON) ([]UserJSON, error) {
-	// Check that order matches one of the defined order constants
-	if userOptions.Order != descOrder && userOptions.Order != ascOrder {
-		return nil, errors.New("User Range order must be ascending or descending")
+// GetUserRangeJSON returns a page of users along with opaque cursors for
+// the next and previous pages. nextCursor/prevCursor are empty when the
+// page is empty, since there is nothing to page from.
+func GetUserRangeJSON(db *sqlx.DB, userOptions UserOptionsJSON) (users []UserJSON, nextCursor string, prevCursor string, err error) {
+	order, limit, err := resolveUserRangeCursor(userOptions)
+	if err != nil {
+		return nil, "", "", err
 	}
-	users, err := getUserRange(db, userOptions)
+
+	extraUsers, err := getUserRange(db, userOptions)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
+	}
+	users = buildUserJSONArray(extraUsers)
+
+	if len(extraUsers) == 0 {
+		return users, "", "", nil
 	}
-	return buildUserJSONArray(users), nil
+
+	first := extraUsers[0]
+	last := extraUsers[len(extraUsers)-1]
+
+	nextCursor, err = signCursor(cursorPayload{LastName: last.Name, LastID: last.ID, Order: order, Limit: limit})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	reverseOrder := OrderAsc
+	if order == OrderAsc {
+		reverseOrder = OrderDesc
+	}
+	prevCursor, err = signCursor(cursorPayload{LastName: first.Name, LastID: first.ID, Order: reverseOrder, Limit: limit})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return users, nextCursor, prevCursor, nil
 }
 
 func getUsersJSON(db *sqlx.DB, userID int) ([]UserJSON, error) {
-	users, err := GetUsersExtra(db, userID)
+	users, err := GetUsersExtra(db, userID, FindUsersOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +272,7 @@ func buildUserJSONArray(users []UserExtra) []UserJSON {
 }
 
 func GetUser(db *sqlx.DB, name string) (User, error) {
-	users, err := getUsers(db, name)
+	users, err := getUsers(db, name, FindUsersOptions{})
 	if err != nil {
 		return User{}, err
 	} else if len(users) == 0 {
@@ -196,19 +283,29 @@ func GetUser(db *sqlx.DB, name string) (User, error) {
 	return users[0], nil
 }
 
-func GetUsers(db *sqlx.DB) ([]User, error) {
-	return getUsers(db, "")
+func GetUsers(db *sqlx.DB, opts FindUsersOptions) ([]User, error) {
+	return getUsers(db, "", opts)
 }
 
-func getUsers(db *sqlx.DB, name string) ([]User, error) {
+func getUsers(db *sqlx.DB, name string, opts FindUsersOptions) ([]User, error) {
+	var conditions []string
 	var queryArgs []interface{}
 	query := selectUserBaseQuery
 
 	if name != "" {
-		query += " WHERE name = ? "
+		conditions = append(conditions, "name = ?")
 		queryArgs = append(queryArgs, name)
 	}
 
+	if clause, arg, ok := buildRowStatusFilter(opts.RowStatus); ok {
+		conditions = append(conditions, clause)
+		queryArgs = append(queryArgs, arg)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
 	query += " ORDER BY name "
 
 	var users []User
@@ -219,16 +316,26 @@ func getUsers(db *sqlx.DB, name string) ([]User, error) {
 	return users, nil
 }
 
-func GetUsersExtra(db *sqlx.DB, userID int) ([]UserExtra, error) {
+func GetUsersExtra(db *sqlx.DB, userID int, opts FindUsersOptions) ([]UserExtra, error) {
 	query := selectUserExtraBaseQuery
 
+	var conditions []string
 	var queryArgs []interface{}
 
 	if userID != 0 {
 		// retrieve specific user rather than all users
-		query += " WHERE a.id = ? "
+		conditions = append(conditions, "a.id = ?")
 		queryArgs = append(queryArgs, userID)
 	}
+
+	if clause, arg, ok := buildRowStatusFilter(opts.RowStatus); ok {
+		conditions = append(conditions, clause)
+		queryArgs = append(queryArgs, arg)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
 	query += " GROUP BY a.id "
 
 	var users []UserExtra
@@ -245,34 +352,14 @@ func GetUsersExtra(db *sqlx.DB, userID int) ([]UserExtra, error) {
 }
 
 func getUserRange(db *sqlx.DB, userOptions UserOptionsJSON) ([]UserExtra, error) {
-	query := selectUserExtraBaseQuery
-	name := userOptions.Name
-	order := userOptions.Order
-	limit := userOptions.Limit
-	var queryArgs []interface{}
-
-	if name != "" {
-		if order == descOrder {
-			query += " WHERE a.name < ? "
-		} else {
-			query += " WHERE a.name > ? "
-		}
-		queryArgs = append(queryArgs, name)
-	}
-
-	query += " GROUP BY a.name ORDER BY a.name "
-	if order == descOrder {
-		query += "desc "
-	}
-
-	if limit > 0 {
-		query += " LIMIT ? "
-		queryArgs = append(queryArgs, limit)
+	query, queryArgs, lastName, err := buildUserRangeQuery(userOptions)
+	if err != nil {
+		return nil, err
 	}
 
 	var users []UserExtra
 	if err := db.Select(&users, query, queryArgs...); err != nil {
-		return nil, errors.Wrapf(err, "failed to retrieve %d users before/after %s", limit, name)
+		return nil, errors.Wrapf(err, "failed to retrieve %d users before/after %s", userOptions.Limit, lastName)
 	}
 
 	return users, nil
@@ -297,13 +384,19 @@ WHERE
 	return data, nil
 }
 
-func GetOrCreateUser(db *sqlx.DB, name string) (User, error) {
+// GetOrCreateUser looks up an activist by name, creating them as actor if
+// no such activist exists yet. actor must hold PermissionCreateActivist.
+func GetOrCreateUser(db *sqlx.DB, actor User, name string) (User, error) {
 	user, err := GetUser(db, name)
 	if err == nil {
 		// We got a valid user, return them.
 		return user, nil
 	}
 
+	if err := CheckPermission(actor, PermissionCreateActivist); err != nil {
+		return User{}, err
+	}
+
 	// There was an error, so try inserting the user first.
 	// Wrap in transaction to avoid issue where a new user
 	// is inserted successfully, but we are unable to retrieve
@@ -330,6 +423,11 @@ func GetOrCreateUser(db *sqlx.DB, name string) (User, error) {
 		return User{}, errors.Wrapf(err, "failed to get new user %s", name)
 	}
 
+	if err := insertAuditLog(tx, actor.ID, newUser.ID, "{}"); err != nil {
+		tx.Rollback()
+		return User{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		tx.Rollback()
 		return User{}, errors.Wrapf(err, "failed to commit user %s", name)
@@ -338,8 +436,52 @@ func GetOrCreateUser(db *sqlx.DB, name string) (User, error) {
 	return newUser, nil
 }
 
-func UpdateActivistData(db *sqlx.DB, user UserExtra) (int, error) {
-	_, err := db.NamedExec(`UPDATE activists
+// UpdateActivistData updates an activist row and records the change as an
+// audit log entry in the same transaction. actor is the user performing the
+// update: they must hold PermissionUpdateOwnProfile to edit themselves,
+// PermissionUpdateAnyProfile to edit someone else, and
+// PermissionUpdateLeaderboardFields to change core_staff,
+// exclude_from_leaderboard, global_team_member, or activist_level. The
+// update is refused if no activist with user.ID exists; a stale id is
+// detected this way rather than via the UPDATE's affected row count, since
+// a legitimate no-op save (new values equal the old ones) also reports zero
+// rows affected.
+func UpdateActivistData(db *sqlx.DB, actor User, user UserExtra) (int, error) {
+	if actor.ID == user.ID {
+		if err := CheckPermission(actor, PermissionUpdateOwnProfile); err != nil {
+			return 0, err
+		}
+	} else {
+		if err := CheckPermission(actor, PermissionUpdateAnyProfile); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+
+	var oldUser UserExtra
+	query := selectUserExtraBaseQuery + " WHERE a.id = ? GROUP BY a.id "
+	if err := tx.Get(&oldUser, query, user.ID); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to get activist %d before update", user.ID)
+	}
+
+	if oldUser.RowStatus == RowStatusArchived {
+		tx.Rollback()
+		return 0, errors.Errorf("activist %d is archived and cannot be updated", user.ID)
+	}
+
+	if leaderboardFieldsChanged(oldUser, user) {
+		if err := CheckPermission(actor, PermissionUpdateLeaderboardFields); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	_, err = tx.NamedExec(`UPDATE activists
 SET
   name = :name,
   email = :email,
@@ -356,7 +498,76 @@ WHERE
 id = :id`, user)
 
 	if err != nil {
+		tx.Rollback()
 		return 0, errors.Wrap(err, "failed to update activist data")
 	}
+
+	diff, err := diffUserExtra(oldUser, user)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := insertAuditLog(tx, actor.ID, user.ID, diff); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to commit activist update for %d", user.ID)
+	}
+
 	return user.ID, nil
 }
+
+// ArchiveUser soft-deletes an activist by marking them ARCHIVED rather than
+// removing the row, so attendance history is preserved. It refuses to
+// archive a row that isn't currently active.
+func ArchiveUser(db *sqlx.DB, id int, reason string) error {
+	result, err := db.Exec(`UPDATE activists
+SET
+  row_status = ?,
+  deleted_at = NOW(),
+  delete_reason = ?
+WHERE
+  id = ? AND row_status = ?`, RowStatusArchived, reason, id, RowStatusActive)
+	if err != nil {
+		return errors.Wrapf(err, "failed to archive activist %d", id)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "failed to determine rows affected archiving activist %d", id)
+	}
+	if rowsAffected == 0 {
+		return errors.Errorf("no active activist with id %d to archive", id)
+	}
+
+	return nil
+}
+
+// RestoreUser reverses ArchiveUser, returning an archived or hidden activist
+// to ACTIVE status.
+func RestoreUser(db *sqlx.DB, id int) error {
+	result, err := db.Exec(`UPDATE activists
+SET
+  row_status = ?,
+  deleted_at = NULL,
+  delete_reason = NULL
+WHERE
+  id = ? AND row_status != ?`, RowStatusActive, id, RowStatusActive)
+	if err != nil {
+		return errors.Wrapf(err, "failed to restore activist %d", id)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "failed to determine rows affected restoring activist %d", id)
+	}
+	if rowsAffected == 0 {
+		return errors.Errorf("no archived activist with id %d to restore", id)
+	}
+
+	return nil
+}
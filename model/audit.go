@@ -0,0 +1,135 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/** Constant and Variable Definitions */
+
+const selectAuditLogBaseQuery string = `
+SELECT
+  id,
+  actor_id,
+  activist_id,
+  created_at,
+  diff
+FROM audits
+`
+
+// auditableFields lists the UserExtra columns that are diffed and recorded
+// whenever an activist row is mutated via UpdateActivistData.
+var auditableFields = []string{
+	"name",
+	"email",
+	"chapter",
+	"phone",
+	"location",
+	"facebook",
+	"activist_level",
+	"exclude_from_leaderboard",
+	"core_staff",
+	"global_team_member",
+	"liberation_pledge",
+}
+
+/** Type Definitions */
+
+// AuditLog is an immutable record of a single mutation to an activist row.
+type AuditLog struct {
+	ID         int       `db:"id"`
+	ActorID    int       `db:"actor_id"`
+	ActivistID int       `db:"activist_id"`
+	CreatedAt  time.Time `db:"created_at"`
+	Diff       string    `db:"diff"`
+}
+
+// fieldDiff captures the before/after value of a single changed column.
+type fieldDiff struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+/** Functions and Methods */
+
+// GetActivistAuditLog returns the audit trail for activistID, most recent
+// entries first. A limit <= 0 returns the full history.
+func GetActivistAuditLog(db *sqlx.DB, activistID int, limit int) ([]AuditLog, error) {
+	query := selectAuditLogBaseQuery + " WHERE activist_id = ? ORDER BY created_at DESC, id DESC "
+
+	queryArgs := []interface{}{activistID}
+	if limit > 0 {
+		query += " LIMIT ? "
+		queryArgs = append(queryArgs, limit)
+	}
+
+	var logs []AuditLog
+	if err := db.Select(&logs, query, queryArgs...); err != nil {
+		return nil, errors.Wrapf(err, "failed to get audit log for activist %d", activistID)
+	}
+
+	return logs, nil
+}
+
+// diffUserExtra returns a JSON-encoded map of column name to {old, new} for
+// every auditableField that changed between oldUser and newUser. Unchanged
+// fields are omitted.
+func diffUserExtra(oldUser, newUser UserExtra) (string, error) {
+	changes := map[string]fieldDiff{}
+
+	oldValues := map[string]interface{}{
+		"name":                     oldUser.Name,
+		"email":                    oldUser.Email,
+		"chapter":                  oldUser.Chapter,
+		"phone":                    oldUser.Phone,
+		"location":                 oldUser.Location,
+		"facebook":                 oldUser.Facebook,
+		"activist_level":           oldUser.ActivistLevel,
+		"exclude_from_leaderboard": oldUser.ExcludeFromLeaderboard,
+		"core_staff":               oldUser.CoreStaff,
+		"global_team_member":       oldUser.GlobalTeamMember,
+		"liberation_pledge":        oldUser.LiberationPledge,
+	}
+	newValues := map[string]interface{}{
+		"name":                     newUser.Name,
+		"email":                    newUser.Email,
+		"chapter":                  newUser.Chapter,
+		"phone":                    newUser.Phone,
+		"location":                 newUser.Location,
+		"facebook":                 newUser.Facebook,
+		"activist_level":           newUser.ActivistLevel,
+		"exclude_from_leaderboard": newUser.ExcludeFromLeaderboard,
+		"core_staff":               newUser.CoreStaff,
+		"global_team_member":       newUser.GlobalTeamMember,
+		"liberation_pledge":        newUser.LiberationPledge,
+	}
+
+	for _, field := range auditableFields {
+		oldVal := oldValues[field]
+		newVal := newValues[field]
+		if oldVal != newVal {
+			changes[field] = fieldDiff{Old: oldVal, New: newVal}
+		}
+	}
+
+	diff, err := json.Marshal(changes)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal activist diff")
+	}
+
+	return string(diff), nil
+}
+
+// insertAuditLog writes a single audit row in the same transaction as the
+// mutation it describes, so the two can never diverge.
+func insertAuditLog(tx *sqlx.Tx, actorID int, activistID int, diff string) error {
+	_, err := tx.Exec(`INSERT INTO audits (actor_id, activist_id, diff) VALUES (?, ?, ?)`, actorID, activistID, diff)
+	if err != nil {
+		return errors.Wrapf(err, "failed to insert audit log for activist %d", activistID)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Order is the sort direction for a keyset-paginated user range.
+type Order string
+
+const (
+	OrderAsc  Order = "asc"
+	OrderDesc Order = "desc"
+)
+
+// CursorSigningKey signs the opaque cursors returned by GetUserRangeJSON so
+// clients can't tamper with the (name, id, order, limit) they encode. It
+// defaults to the CURSOR_SIGNING_KEY environment variable; set it directly
+// in tests or at startup if that variable isn't present.
+var CursorSigningKey = []byte(os.Getenv("CURSOR_SIGNING_KEY"))
+
+/** Type Definitions */
+
+// cursorPayload is the plaintext (name, id, order, limit) tuple a cursor
+// encodes. LastName/LastID identify the row the following page continues
+// from (the last row of the page for a next cursor, the first row for a
+// previous cursor), so that page's predicate is (name, id) > (LastName,
+// LastID), or < when Order is OrderDesc.
+type cursorPayload struct {
+	LastName string `json:"last_name"`
+	LastID   int    `json:"last_id"`
+	Order    Order  `json:"order"`
+	Limit    int    `json:"limit"`
+}
+
+/** Functions and Methods */
+
+// signCursor encodes payload and appends an HMAC so parseCursor can detect
+// tampering.
+func signCursor(payload cursorPayload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal cursor payload")
+	}
+
+	mac := hmac.New(sha256.New, CursorSigningKey)
+	mac.Write(data)
+	sig := mac.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseCursor verifies the HMAC on cursor and decodes its payload.
+func parseCursor(cursor string) (cursorPayload, error) {
+	parts := strings.SplitN(cursor, ".", 2)
+	if len(parts) != 2 {
+		return cursorPayload{}, errors.New("malformed cursor")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return cursorPayload{}, errors.Wrap(err, "failed to decode cursor payload")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return cursorPayload{}, errors.Wrap(err, "failed to decode cursor signature")
+	}
+
+	mac := hmac.New(sha256.New, CursorSigningKey)
+	mac.Write(data)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return cursorPayload{}, errors.New("cursor signature is invalid")
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return cursorPayload{}, errors.Wrap(err, "failed to unmarshal cursor payload")
+	}
+
+	return payload, nil
+}
+
+// resolveUserRangeCursor decodes userOptions.Cursor if set, otherwise falls
+// back to userOptions.Order/Limit, and validates the resulting order.
+func resolveUserRangeCursor(userOptions UserOptionsJSON) (order Order, limit int, err error) {
+	order, limit = userOptions.Order, userOptions.Limit
+
+	if userOptions.Cursor != "" {
+		payload, err := parseCursor(userOptions.Cursor)
+		if err != nil {
+			return "", 0, err
+		}
+		order, limit = payload.Order, payload.Limit
+	}
+
+	if order != OrderAsc && order != OrderDesc {
+		return "", 0, errors.New("User Range order must be ascending or descending")
+	}
+
+	return order, limit, nil
+}
+
+// buildUserRangeQuery builds the SQL and bind args for a keyset-paginated
+// page of activists. The (a.name, a.id) tuple comparison, rather than a
+// bare name comparison, keeps pagination stable across activists who share
+// a name: a bare "name > ?" predicate would either repeat or silently drop
+// rows once the cursor lands inside a run of same-named activists.
+func buildUserRangeQuery(userOptions UserOptionsJSON) (query string, queryArgs []interface{}, lastName string, err error) {
+	order, limit, err := resolveUserRangeCursor(userOptions)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	lastName = userOptions.Name
+	lastID := 0
+
+	if userOptions.Cursor != "" {
+		payload, err := parseCursor(userOptions.Cursor)
+		if err != nil {
+			return "", nil, "", err
+		}
+		lastName, lastID = payload.LastName, payload.LastID
+	}
+
+	query = selectUserExtraBaseQuery
+	var conditions []string
+
+	if lastName != "" {
+		if order == OrderDesc {
+			conditions = append(conditions, "(a.name, a.id) < (?, ?)")
+		} else {
+			conditions = append(conditions, "(a.name, a.id) > (?, ?)")
+		}
+		queryArgs = append(queryArgs, lastName, lastID)
+	}
+
+	if clause, arg, ok := buildRowStatusFilter(userOptions.RowStatus); ok {
+		conditions = append(conditions, clause)
+		queryArgs = append(queryArgs, arg)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " GROUP BY a.id ORDER BY a.name "
+	if order == OrderDesc {
+		query += "desc, a.id desc "
+	} else {
+		query += ", a.id "
+	}
+
+	if limit > 0 {
+		query += " LIMIT ? "
+		queryArgs = append(queryArgs, limit)
+	}
+
+	return query, queryArgs, lastName, nil
+}
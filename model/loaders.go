@@ -0,0 +1,183 @@
+package model
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/** Type Definitions */
+
+// Loaders batches and caches the model lookups needed to serve a single
+// request, so handlers that fan out over a list of activists (e.g. a
+// leaderboard) don't issue one query per activist.
+type Loaders struct {
+	UsersByID *userByIDLoader
+}
+
+// UserResult is one entry of a LoadAll response: the loaded row, or a nil
+// User and non-nil Err if id could not be found.
+type UserResult struct {
+	User *UserExtra
+	Err  error
+}
+
+// pendingLoad is one caller's id waiting on the next batched fetch.
+type pendingLoad struct {
+	id     int
+	result chan UserResult
+}
+
+type userByIDLoader struct {
+	db *sqlx.DB
+
+	mu      sync.Mutex
+	cache   map[int]UserExtra
+	batch   []pendingLoad
+	waiting bool
+}
+
+/** Functions and Methods */
+
+// NewLoaders constructs a fresh, empty Loaders bound to db. Callers should
+// create one per incoming request and discard it once the request completes.
+func NewLoaders(db *sqlx.DB) *Loaders {
+	return &Loaders{
+		UsersByID: &userByIDLoader{
+			db:    db,
+			cache: map[int]UserExtra{},
+		},
+	}
+}
+
+// Load fetches a single activist by id. Any Load calls made concurrently
+// against the same loader, before the first one dispatches, are coalesced
+// into one query.
+func (l *userByIDLoader) Load(ctx context.Context, id int) (UserExtra, error) {
+	l.mu.Lock()
+	if u, ok := l.cache[id]; ok {
+		l.mu.Unlock()
+		return u, nil
+	}
+
+	resultCh := make(chan UserResult, 1)
+	l.batch = append(l.batch, pendingLoad{id: id, result: resultCh})
+	shouldDispatch := !l.waiting
+	l.waiting = true
+	l.mu.Unlock()
+
+	if shouldDispatch {
+		go l.dispatch()
+	}
+
+	select {
+	case result := <-resultCh:
+		if result.User == nil {
+			return UserExtra{}, result.Err
+		}
+		return *result.User, nil
+	case <-ctx.Done():
+		return UserExtra{}, ctx.Err()
+	}
+}
+
+// LoadAll fetches every id, backed by the loader's cache, and returns one
+// UserResult per id in the same order as ids. Each id goes through Load, so
+// ids (from here or any other concurrent Load/LoadAll call on this loader)
+// enqueued before the pending dispatch runs share its batched query; ids
+// enqueued after it start the next batch.
+func (l *userByIDLoader) LoadAll(ctx context.Context, ids []int) []UserResult {
+	results := make([]UserResult, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			user, err := l.Load(ctx, id)
+			if err != nil {
+				results[i] = UserResult{Err: err}
+				return
+			}
+			u := user
+			results[i] = UserResult{User: &u}
+		}(i, id)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// dispatch yields once to let other already-runnable goroutines enqueue
+// into the current batch, then fetches every pending id in a single query
+// and fans the results out to each waiting caller.
+func (l *userByIDLoader) dispatch() {
+	runtime.Gosched()
+
+	l.mu.Lock()
+	batch := l.batch
+	l.batch = nil
+	l.waiting = false
+	l.mu.Unlock()
+
+	ids := make([]int, 0, len(batch))
+	seen := map[int]bool{}
+	for _, req := range batch {
+		if !seen[req.id] {
+			seen[req.id] = true
+			ids = append(ids, req.id)
+		}
+	}
+
+	users, err := l.fetch(ids)
+
+	l.mu.Lock()
+	if err == nil {
+		for _, u := range users {
+			l.cache[u.ID] = u
+		}
+	}
+	l.mu.Unlock()
+
+	for _, req := range batch {
+		if err != nil {
+			req.result <- UserResult{Err: err}
+			continue
+		}
+		l.mu.Lock()
+		user, ok := l.cache[req.id]
+		l.mu.Unlock()
+		if !ok {
+			req.result <- UserResult{Err: errors.Errorf("no activist found for id %d", req.id)}
+			continue
+		}
+		u := user
+		req.result <- UserResult{User: &u}
+	}
+}
+
+// fetch loads every id in ids with a single batched query, joining event
+// attendance in the same GROUP BY activist_id shape as GetUsersExtra so
+// UserEventData is back-filled without a per-user scan.
+func (l *userByIDLoader) fetch(ids []int) ([]UserExtra, error) {
+	query, args, err := sqlx.In(selectUserExtraBaseQuery+" WHERE a.id IN (?) GROUP BY a.id ", ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build batched activist query")
+	}
+	query = l.db.Rebind(query)
+
+	var users []UserExtra
+	if err := l.db.Select(&users, query, args...); err != nil {
+		return nil, errors.Wrapf(err, "failed to batch load activists %v", ids)
+	}
+
+	for i := range users {
+		users[i].Status = getStatus(users[i].FirstEvent, users[i].LastEvent, users[i].TotalEvents)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Role is the access level assigned to an activist via activists.role.
+type Role string
+
+const (
+	RoleAdmin     Role = "ADMIN"
+	RoleOrganizer Role = "ORGANIZER"
+	RoleMember    Role = "MEMBER"
+	RoleReadOnly  Role = "READ_ONLY"
+)
+
+// Permission identifies a single privileged model operation.
+type Permission string
+
+const (
+	// PermissionUpdateOwnProfile lets a user edit their own name, email,
+	// chapter, phone, location, facebook, and liberation_pledge.
+	PermissionUpdateOwnProfile Permission = "UPDATE_OWN_PROFILE"
+	// PermissionUpdateAnyProfile lets a user edit another activist's basic
+	// profile fields.
+	PermissionUpdateAnyProfile Permission = "UPDATE_ANY_PROFILE"
+	// PermissionUpdateLeaderboardFields lets a user change core_staff,
+	// exclude_from_leaderboard, global_team_member, or activist_level.
+	PermissionUpdateLeaderboardFields Permission = "UPDATE_LEADERBOARD_FIELDS"
+	// PermissionCreateActivist lets a user create a new activist record.
+	PermissionCreateActivist Permission = "CREATE_ACTIVIST"
+)
+
+// rolePermissions is the authoritative mapping of which roles hold which
+// permissions. ReadOnly holds none, since it may only view data.
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleAdmin: {
+		PermissionUpdateOwnProfile:        true,
+		PermissionUpdateAnyProfile:        true,
+		PermissionUpdateLeaderboardFields: true,
+		PermissionCreateActivist:          true,
+	},
+	RoleOrganizer: {
+		PermissionUpdateOwnProfile: true,
+		PermissionUpdateAnyProfile: true,
+		PermissionCreateActivist:   true,
+	},
+	RoleMember: {
+		PermissionUpdateOwnProfile: true,
+		PermissionCreateActivist:   true,
+	},
+	RoleReadOnly: {},
+}
+
+/** Functions and Methods */
+
+// CheckPermission returns an error unless user's role grants perm.
+func CheckPermission(user User, perm Permission) error {
+	if rolePermissions[user.Role][perm] {
+		return nil
+	}
+	return errors.Errorf("user %d with role %q does not have permission %q", user.ID, user.Role, perm)
+}
+
+// leaderboardFieldsChanged reports whether any leaderboard-affecting field
+// differs between oldUser and newUser.
+func leaderboardFieldsChanged(oldUser, newUser UserExtra) bool {
+	return oldUser.CoreStaff != newUser.CoreStaff ||
+		oldUser.ExcludeFromLeaderboard != newUser.ExcludeFromLeaderboard ||
+		oldUser.GlobalTeamMember != newUser.GlobalTeamMember ||
+		oldUser.ActivistLevel != newUser.ActivistLevel
+}
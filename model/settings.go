@@ -0,0 +1,182 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/** Constant and Variable Definitions */
+
+const selectUserSettingsQuery string = `
+SELECT
+  timezone,
+  locale,
+  email_notifications_enabled,
+  leaderboard_display_name,
+  custom_preferences
+FROM activists
+WHERE id = ?
+`
+
+// allowedCustomPreferenceIcons is the set of icon names a CustomPreference
+// may reference. Any other value fails validation before it reaches the DB.
+var allowedCustomPreferenceIcons = map[string]bool{
+	"star":      true,
+	"heart":     true,
+	"flag":      true,
+	"bell":      true,
+	"pin":       true,
+	"bookmark":  true,
+	"checkmark": true,
+}
+
+var customPreferenceKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+/** Type Definitions */
+
+// ValidationError is a structured error identifying the offending field, so
+// callers can surface it to a user without parsing an error string.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// CustomPreference is a single user-defined UI preference, e.g. pinning a
+// dashboard widget with a chosen icon and tooltip.
+type CustomPreference struct {
+	Icon      string `json:"icon"`
+	Tooltip   string `json:"tooltip"`
+	Size      string `json:"size"`
+	Muted     bool   `json:"muted"`
+	Favourite bool   `json:"favourite"`
+}
+
+// UserSettings holds an activist's account preferences.
+type UserSettings struct {
+	Timezone                  string                      `json:"timezone" db:"timezone"`
+	Locale                    string                      `json:"locale" db:"locale"`
+	EmailNotificationsEnabled int                         `json:"email_notifications_enabled" db:"email_notifications_enabled"`
+	LeaderboardDisplayName    string                      `json:"leaderboard_display_name" db:"leaderboard_display_name"`
+	CustomPreferences         map[string]CustomPreference `json:"custom_preferences" db:"-"`
+}
+
+// userSettingsRow mirrors the activists columns backing UserSettings.
+// CustomPreferences is stored as a single JSON text column, so it's scanned
+// separately and unmarshalled by hand rather than through sqlx struct tags.
+// The string columns are nullable until every activist row is backfilled
+// with settings defaults, so they're scanned as sql.NullString rather than
+// plain string.
+type userSettingsRow struct {
+	Timezone                  sql.NullString `db:"timezone"`
+	Locale                    sql.NullString `db:"locale"`
+	EmailNotificationsEnabled int            `db:"email_notifications_enabled"`
+	LeaderboardDisplayName    sql.NullString `db:"leaderboard_display_name"`
+	CustomPreferences         sql.NullString `db:"custom_preferences"`
+}
+
+/** Functions and Methods */
+
+// GetUserSettings returns id's account preferences.
+func GetUserSettings(db *sqlx.DB, id int) (UserSettings, error) {
+	var row userSettingsRow
+	if err := db.Get(&row, selectUserSettingsQuery, id); err != nil {
+		return UserSettings{}, errors.Wrapf(err, "failed to get settings for activist %d", id)
+	}
+
+	prefs, err := unmarshalCustomPreferences(row.CustomPreferences.String)
+	if err != nil {
+		return UserSettings{}, errors.Wrapf(err, "failed to parse settings for activist %d", id)
+	}
+
+	return UserSettings{
+		Timezone:                  row.Timezone.String,
+		Locale:                    row.Locale.String,
+		EmailNotificationsEnabled: row.EmailNotificationsEnabled,
+		LeaderboardDisplayName:    row.LeaderboardDisplayName.String,
+		CustomPreferences:         prefs,
+	}, nil
+}
+
+// UpdateUserSettings validates settings and persists them for id. Validation
+// happens before the NamedExec so a bad custom preference never reaches the
+// database.
+func UpdateUserSettings(db *sqlx.DB, id int, settings UserSettings) error {
+	if err := validateCustomPreferences(settings.CustomPreferences); err != nil {
+		return err
+	}
+
+	customPreferencesJSON, err := json.Marshal(settings.CustomPreferences)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal custom preferences")
+	}
+
+	row := struct {
+		ID int `db:"id"`
+		userSettingsRow
+	}{
+		ID: id,
+		userSettingsRow: userSettingsRow{
+			Timezone:                  sql.NullString{String: settings.Timezone, Valid: true},
+			Locale:                    sql.NullString{String: settings.Locale, Valid: true},
+			EmailNotificationsEnabled: settings.EmailNotificationsEnabled,
+			LeaderboardDisplayName:    sql.NullString{String: settings.LeaderboardDisplayName, Valid: true},
+			CustomPreferences:         sql.NullString{String: string(customPreferencesJSON), Valid: true},
+		},
+	}
+
+	_, err = db.NamedExec(`UPDATE activists
+SET
+  timezone = :timezone,
+  locale = :locale,
+  email_notifications_enabled = :email_notifications_enabled,
+  leaderboard_display_name = :leaderboard_display_name,
+  custom_preferences = :custom_preferences
+WHERE
+  id = :id`, row)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update settings for activist %d", id)
+	}
+
+	return nil
+}
+
+func unmarshalCustomPreferences(raw string) (map[string]CustomPreference, error) {
+	prefs := map[string]CustomPreference{}
+	if raw == "" {
+		return prefs, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// validateCustomPreferences checks every key and icon before it's written,
+// returning the first violation found as a ValidationError.
+func validateCustomPreferences(prefs map[string]CustomPreference) error {
+	for key, pref := range prefs {
+		if !customPreferenceKeyPattern.MatchString(key) {
+			return ValidationError{
+				Field:   "custom_preferences." + key,
+				Message: "key must be 1-32 characters of letters, digits, underscore, or hyphen",
+			}
+		}
+		if !allowedCustomPreferenceIcons[pref.Icon] {
+			return ValidationError{
+				Field:   "custom_preferences." + key + ".icon",
+				Message: fmt.Sprintf("icon %q is not in the allowed icon set", pref.Icon),
+			}
+		}
+	}
+	return nil
+}